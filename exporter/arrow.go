@@ -0,0 +1,148 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"errors"
+	"io"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+
+	"github.com/euracresearch/toa5"
+)
+
+// defaultBatchSize is the number of rows grouped into one Arrow record
+// batch when ExportOptions.BatchSize is unset.
+const defaultBatchSize = 1000
+
+// ToArrow reads r to completion and writes its Records to w as an Arrow IPC
+// stream: one "timestamp" column plus one float64 column per named field,
+// flushed as a record batch every opts.BatchSize rows (default 1000).
+// Columns are derived once from r.Fields(), so a row missing a value for a
+// given column is written as null rather than widening the schema.
+func ToArrow(r toa5.RecordReader, w io.Writer, opts ExportOptions) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	names := make([]string, 0, len(r.Fields()))
+	index := make(map[string]int, len(r.Fields()))
+	for _, f := range r.Fields() {
+		if f == "" {
+			continue
+		}
+		if _, ok := index[f]; ok {
+			continue
+		}
+		index[f] = len(names)
+		names = append(names, f)
+	}
+
+	schemaFields := make([]arrow.Field, 0, len(names)+1)
+	schemaFields = append(schemaFields, arrow.Field{Name: "timestamp", Type: arrow.FixedWidthTypes.Timestamp_ns})
+	for _, n := range names {
+		schemaFields = append(schemaFields, arrow.Field{Name: n, Type: arrow.PrimitiveTypes.Float64, Nullable: true})
+	}
+	schema := arrow.NewSchema(schemaFields, nil)
+
+	iw := ipc.NewWriter(w, ipc.WithSchema(schema))
+	defer iw.Close()
+
+	pool := memory.NewGoAllocator()
+	tsBuilder := array.NewTimestampBuilder(pool, arrow.FixedWidthTypes.Timestamp_ns.(*arrow.TimestampType))
+	valueBuilders := make([]*array.Float64Builder, len(names))
+	for i := range valueBuilders {
+		valueBuilders[i] = array.NewFloat64Builder(pool)
+	}
+
+	var (
+		rows     int
+		rowSeen  bool
+		rowReady bool
+		rowTS    arrow.Timestamp
+		rowVals  = make([]float64, len(names))
+		rowSet   = make([]bool, len(names))
+	)
+
+	appendRow := func() error {
+		tsBuilder.Append(rowTS)
+		for i, b := range valueBuilders {
+			if rowSet[i] {
+				b.Append(rowVals[i])
+			} else {
+				b.AppendNull()
+			}
+			rowSet[i] = false
+		}
+
+		rows++
+		if rows < batchSize {
+			return nil
+		}
+		return writeBatch(iw, schema, tsBuilder, valueBuilders, &rows)
+	}
+
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, toa5.ErrEmptyRecord) {
+				continue
+			}
+			return err
+		}
+
+		ts := arrow.Timestamp(rec.Timestamp.UnixNano())
+		if rowSeen && ts != rowTS {
+			if err := appendRow(); err != nil {
+				return err
+			}
+			rowReady = false
+		}
+
+		rowTS = ts
+		rowSeen = true
+		rowReady = true
+		if i, ok := index[rec.Name]; ok {
+			rowVals[i] = rec.Value
+			rowSet[i] = true
+		}
+	}
+
+	if rowReady {
+		if err := appendRow(); err != nil {
+			return err
+		}
+	}
+
+	return writeBatch(iw, schema, tsBuilder, valueBuilders, &rows)
+}
+
+func writeBatch(w *ipc.Writer, schema *arrow.Schema, tsBuilder *array.TimestampBuilder, valueBuilders []*array.Float64Builder, rows *int) error {
+	if *rows == 0 {
+		return nil
+	}
+
+	cols := make([]arrow.Array, 0, len(valueBuilders)+1)
+	cols = append(cols, tsBuilder.NewArray())
+	for _, b := range valueBuilders {
+		cols = append(cols, b.NewArray())
+	}
+
+	batch := array.NewRecord(schema, cols, int64(*rows))
+	defer batch.Release()
+	for _, c := range cols {
+		c.Release()
+	}
+
+	*rows = 0
+	return w.Write(batch)
+}
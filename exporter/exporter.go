@@ -0,0 +1,27 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package exporter streams the Records of a toa5.RecordReader out in
+// formats used by common time-series ingestion pipelines: InfluxDB line
+// protocol, Prometheus remote-write and Apache Arrow IPC.
+package exporter
+
+import "strings"
+
+// ExportOptions configures the exporter functions.
+type ExportOptions struct {
+	// BatchSize is the number of rows grouped into a single Arrow record
+	// batch by ToArrow. It is ignored by ToLineProtocol and
+	// ToPrometheusRemoteWrite. A value <= 0 defaults to 1000.
+	BatchSize int
+}
+
+var escaper = strings.NewReplacer(" ", `\ `, ",", `\,`, "=", `\=`)
+
+// escape escapes the characters InfluxDB line protocol treats as
+// structural (space, comma and equals sign) in a measurement, tag or field
+// key.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/euracresearch/toa5"
+)
+
+const fixture = `TOA5,Station,CR1000,S11,CR1000.Std.32.03,CPU:T1.CR1,4242,Table
+TIMESTAMP,RECORD,Batt_V_Avg,,,,,
+TS,RN,Volts,,,,,
+,,Avg,,,,,
+2020-06-07 23:45,0,12.52,,,,,
+2020-06-08 00:00,1,12.56,,,,,
+`
+
+func TestToLineProtocol(t *testing.T) {
+	r, err := toa5.NewReader(strings.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ToLineProtocol(r, &buf, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+
+	if !strings.HasPrefix(lines[0], "Table,station=Station RECORD=0,Batt_V_Avg=12.52 ") {
+		t.Fatalf("unexpected first line: %q", lines[0])
+	}
+}
+
+func TestToPrometheusRemoteWrite(t *testing.T) {
+	r, err := toa5.NewReader(strings.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ToPrometheusRemoteWrite(r, &buf, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := snappy.Decode(nil, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req prompb.WriteRequest
+	if err := req.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.Timeseries) != 2 {
+		t.Fatalf("got %d series, want 2", len(req.Timeseries))
+	}
+
+	for _, ts := range req.Timeseries {
+		if len(ts.Samples) != 2 {
+			t.Fatalf("series %v: got %d samples, want 2", ts.Labels, len(ts.Samples))
+		}
+	}
+}
+
+func TestToArrow(t *testing.T) {
+	r, err := toa5.NewReader(strings.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ToArrow(r, &buf, ExportOptions{BatchSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	ir, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ir.Release()
+
+	var gotBatt, gotRecord []float64
+	for ir.Next() {
+		rec := ir.Record()
+		sc := rec.Schema()
+
+		battIdx := sc.FieldIndices("Batt_V_Avg")
+		recordIdx := sc.FieldIndices("RECORD")
+		if len(battIdx) != 1 || len(recordIdx) != 1 {
+			t.Fatalf("schema missing expected fields: %v", sc)
+		}
+
+		batt := rec.Column(battIdx[0])
+		record := rec.Column(recordIdx[0])
+		for i := 0; i < int(rec.NumRows()); i++ {
+			if batt.IsNull(i) {
+				t.Fatalf("row %d: Batt_V_Avg is null", i)
+			}
+			gotBatt = append(gotBatt, batt.(interface{ Value(int) float64 }).Value(i))
+			gotRecord = append(gotRecord, record.(interface{ Value(int) float64 }).Value(i))
+		}
+	}
+	if err := ir.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBatt := []float64{12.52, 12.56}
+	wantRecord := []float64{0, 1}
+	if len(gotBatt) != len(wantBatt) {
+		t.Fatalf("got %d rows, want %d", len(gotBatt), len(wantBatt))
+	}
+	for i := range wantBatt {
+		if gotBatt[i] != wantBatt[i] || gotRecord[i] != wantRecord[i] {
+			t.Fatalf("row %d: got Batt_V_Avg=%v RECORD=%v, want %v/%v", i, gotBatt[i], gotRecord[i], wantBatt[i], wantRecord[i])
+		}
+	}
+}
+
+// errReader is a toa5.RecordReader stub that returns one record and then a
+// non-EOF, non-ErrEmptyRecord error, to verify that genuine errors are
+// returned to the caller rather than swallowed.
+type errReader struct {
+	calls int
+}
+
+func (r *errReader) Environment() *toa5.Environment {
+	return &toa5.Environment{Table: "Table", Station: "Station"}
+}
+func (r *errReader) Fields() []string      { return []string{"TIMESTAMP", "RECORD"} }
+func (r *errReader) Units() []string       { return nil }
+func (r *errReader) Aggregation() []string { return nil }
+
+var errBoom = errors.New("boom")
+
+func (r *errReader) Read() (*toa5.Record, error) {
+	r.calls++
+	if r.calls == 1 {
+		return &toa5.Record{Timestamp: time.Date(2020, 6, 7, 23, 45, 0, 0, time.UTC), Value: 0, Name: "RECORD"}, nil
+	}
+	return nil, errBoom
+}
+
+func TestExportPropagatesReadErrors(t *testing.T) {
+	tests := map[string]func(toa5.RecordReader, io.Writer, ExportOptions) error{
+		"ToLineProtocol":          ToLineProtocol,
+		"ToPrometheusRemoteWrite": ToPrometheusRemoteWrite,
+		"ToArrow":                 ToArrow,
+	}
+
+	for name, export := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := export(&errReader{}, &buf, ExportOptions{}); !errors.Is(err, errBoom) {
+				t.Fatalf("got %v, want errBoom", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/euracresearch/toa5"
+)
+
+// ToLineProtocol reads r to completion and writes its Records to w as
+// InfluxDB line protocol: measurement is the table name from r's
+// Environment, the station is written as a tag, and every column sharing a
+// timestamp is written as a field on a single line. NaN values are skipped,
+// since line protocol has no representation for them.
+func ToLineProtocol(r toa5.RecordReader, w io.Writer, opts ExportOptions) error {
+	env := r.Environment()
+	measurement := escape(env.Table)
+	tags := "station=" + escape(env.Station)
+
+	var (
+		cur    time.Time
+		fields []string
+		have   bool
+	)
+
+	flush := func() error {
+		if len(fields) == 0 {
+			return nil
+		}
+
+		_, err := fmt.Fprintf(w, "%s,%s %s %d\n", measurement, tags, strings.Join(fields, ","), cur.UnixNano())
+		fields = fields[:0]
+		return err
+	}
+
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, toa5.ErrEmptyRecord) {
+				// Skip cells the reader couldn't attribute to a named column
+				// (e.g. the empty padding columns some TOA5/TOB1/TOB3 headers
+				// carry) rather than aborting the whole export.
+				continue
+			}
+			return err
+		}
+
+		if math.IsNaN(rec.Value) {
+			continue
+		}
+
+		if have && !rec.Timestamp.Equal(cur) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		cur = rec.Timestamp
+		have = true
+		fields = append(fields, escape(rec.Name)+"="+strconv.FormatFloat(rec.Value, 'f', -1, 64))
+	}
+
+	return flush()
+}
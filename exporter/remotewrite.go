@@ -0,0 +1,79 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"errors"
+	"io"
+	"math"
+	"regexp"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/euracresearch/toa5"
+)
+
+// invalidMetricChars matches any character not allowed in a Prometheus
+// metric or label name.
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// ToPrometheusRemoteWrite reads r to completion and writes its Records to w
+// as a single snappy-compressed Prometheus remote-write WriteRequest
+// protobuf message, one time series per column named "<table>_<field>"
+// with a "station" label. NaN values are skipped, as Prometheus reserves
+// them as internal staleness markers.
+func ToPrometheusRemoteWrite(r toa5.RecordReader, w io.Writer, opts ExportOptions) error {
+	env := r.Environment()
+	table := invalidMetricChars.ReplaceAllString(env.Table, "_")
+
+	series := make(map[string]*prompb.TimeSeries)
+
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, toa5.ErrEmptyRecord) {
+				continue
+			}
+			return err
+		}
+
+		if math.IsNaN(rec.Value) {
+			continue
+		}
+
+		ts, ok := series[rec.Name]
+		if !ok {
+			ts = &prompb.TimeSeries{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: table + "_" + invalidMetricChars.ReplaceAllString(rec.Name, "_")},
+					{Name: "station", Value: env.Station},
+				},
+			}
+			series[rec.Name] = ts
+		}
+
+		ts.Samples = append(ts.Samples, prompb.Sample{
+			Value:     rec.Value,
+			Timestamp: rec.Timestamp.UnixMilli(),
+		})
+	}
+
+	req := &prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(series))}
+	for _, ts := range series {
+		req.Timeseries = append(req.Timeseries, *ts)
+	}
+
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(snappy.Encode(nil, data))
+	return err
+}
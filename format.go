@@ -0,0 +1,76 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// ErrUnknownFormat is returned by Open when none of the registered formats
+// recognize the input.
+var ErrUnknownFormat = errors.New("toa5: unknown format")
+
+// RecordReader is implemented by readers for the Campbell Scientific logger
+// file formats (TOA5, and the binary TOB1/TOB3 supported by the tob1 and
+// tob3 sub-packages) that expose a stream of Records.
+type RecordReader interface {
+	Environment() *Environment
+	Fields() []string
+	Units() []string
+	Aggregation() []string
+	Read() (*Record, error)
+}
+
+// sniffLen is the number of leading bytes Open inspects to detect a file's
+// format.
+const sniffLen = 5
+
+type registeredFormat struct {
+	name      string
+	match     func([]byte) bool
+	newReader func(io.Reader) (RecordReader, error)
+}
+
+var formats []registeredFormat
+
+// RegisterFormat registers a RecordReader implementation for use by Open.
+// Sub-packages implementing a Campbell Scientific logger format call this
+// from an init function, so importing such a package for its side effect
+// (e.g. `import _ "github.com/euracresearch/toa5/tob1"`) is enough to make
+// Open recognize it.
+func RegisterFormat(name string, match func([]byte) bool, newReader func(io.Reader) (RecordReader, error)) {
+	formats = append(formats, registeredFormat{name, match, newReader})
+}
+
+func init() {
+	RegisterFormat("TOA5", func(b []byte) bool {
+		return len(b) >= 4 && string(b[:4]) == "TOA5"
+	}, func(r io.Reader) (RecordReader, error) {
+		return NewReader(r)
+	})
+}
+
+// Open detects the format of r based on its first bytes and returns a
+// RecordReader for it. Only formats registered via RegisterFormat are
+// recognized; blank-import the relevant sub-package to enable TOB1 or TOB3
+// support. It returns ErrUnknownFormat if no registered format matches.
+func Open(r io.Reader) (RecordReader, error) {
+	br := bufio.NewReader(r)
+
+	b, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	for _, f := range formats {
+		if f.match(b) {
+			return f.newReader(br)
+		}
+	}
+
+	return nil, ErrUnknownFormat
+}
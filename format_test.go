@@ -0,0 +1,28 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	r, err := Open(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := r.(*Reader); !ok {
+		t.Fatalf("Open returned %T, want *Reader", r)
+	}
+}
+
+func TestOpenUnknownFormat(t *testing.T) {
+	_, err := Open(strings.NewReader("not a recognized format"))
+	if err != ErrUnknownFormat {
+		t.Fatalf("err = %v, want %v", err, ErrUnknownFormat)
+	}
+}
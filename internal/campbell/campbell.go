@@ -0,0 +1,116 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package campbell holds the binary decoding logic shared by the tob1 and
+// tob3 packages.
+package campbell
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrUnknownType is returned for a column data type this package does not
+// know how to decode.
+var ErrUnknownType = errors.New("campbell: unknown column data type")
+
+// Epoch is the instant TOB1/TOB3 timestamps are relative to: 1990-01-01
+// 00:00:00 UTC.
+var Epoch = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ByteOrder is the byte order used by the CR1000 family for binary data
+// formats.
+var ByteOrder = binary.LittleEndian
+
+// TypeSize returns the size in bytes of the Campbell Scientific column data
+// type t.
+func TypeSize(t string) (int, error) {
+	switch t {
+	case "IEEE4", "FP4", "ULONG", "LONG":
+		return 4, nil
+	case "IEEE8":
+		return 8, nil
+	case "FP2", "USHORT", "SHORT":
+		return 2, nil
+	case "BOOL", "BOOL8", "UBYTE", "BYTE":
+		return 1, nil
+	case "SecNano":
+		return 8, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownType, t)
+	}
+}
+
+// DecodeValue decodes the Campbell Scientific column data type t from b,
+// returning it as a float64.
+func DecodeValue(b []byte, t string) (float64, error) {
+	switch t {
+	case "IEEE4", "FP4":
+		return float64(math.Float32frombits(ByteOrder.Uint32(b))), nil
+	case "IEEE8":
+		return math.Float64frombits(ByteOrder.Uint64(b)), nil
+	case "FP2":
+		return DecodeFP2(ByteOrder.Uint16(b)), nil
+	case "ULONG":
+		return float64(ByteOrder.Uint32(b)), nil
+	case "LONG":
+		return float64(int32(ByteOrder.Uint32(b))), nil
+	case "USHORT":
+		return float64(ByteOrder.Uint16(b)), nil
+	case "SHORT":
+		return float64(int16(ByteOrder.Uint16(b))), nil
+	case "BOOL", "BOOL8", "UBYTE", "BYTE":
+		if b[0] != 0 {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownType, t)
+	}
+}
+
+// FP2 mantissa values with special meaning.
+const (
+	fp2PosInf = 0x1FFF
+	fp2NegInf = 0x1FFE
+	fp2NaN    = 0x1F9F
+)
+
+// DecodeFP2 decodes a Campbell Scientific FP2 value: a 16-bit float with bit
+// 15 as sign, bits 14-13 as a decimal-locator exponent E (the mantissa is
+// divided by 10^E) and bits 12-0 as the unsigned 13-bit mantissa. The
+// mantissa values 0x1FFF and 0x1FFE denote infinity and 0x1F9F denotes NaN.
+func DecodeFP2(raw uint16) float64 {
+	sign := raw&0x8000 != 0
+	exp := (raw >> 13) & 0x3
+	mantissa := raw & 0x1FFF
+
+	switch mantissa {
+	case fp2NaN:
+		return math.NaN()
+	case fp2PosInf, fp2NegInf:
+		if sign {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	}
+
+	v := float64(mantissa) / math.Pow10(int(exp))
+	if sign {
+		v = -v
+	}
+	return v
+}
+
+// SecNano decodes an 8-byte SecNano field (4-byte seconds since Epoch
+// followed by 4-byte nanoseconds within that second, both little-endian)
+// into a time.Time.
+func SecNano(b []byte) time.Time {
+	seconds := ByteOrder.Uint32(b[0:4])
+	nanos := ByteOrder.Uint32(b[4:8])
+	return Epoch.Add(time.Duration(seconds)*time.Second + time.Duration(nanos)*time.Nanosecond)
+}
@@ -0,0 +1,41 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package campbell
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDecodeFP2(t *testing.T) {
+	tests := map[string]struct {
+		raw  uint16
+		want float64
+	}{
+		"zero":     {0x0000, 0},
+		"one":      {0x0001, 1},
+		"tenth":    {0x2001, 0.1},
+		"negative": {0x8001, -1},
+		"nan":      {0x1F9F, math.NaN()},
+		"posInf":   {0x1FFF, math.Inf(1)},
+		"negInf":   {0x9FFE, math.Inf(-1)},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := DecodeFP2(tt.raw)
+			if math.IsNaN(tt.want) {
+				if !math.IsNaN(got) {
+					t.Fatalf("got %v, want NaN", got)
+				}
+				return
+			}
+
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,6 +21,23 @@ var (
 	ErrEmptyRecord       = errors.New("empty record name")
 	ErrNoOptions         = errors.New("no options provided")
 	ErrEnvironmentLength = errors.New("environment line has missing fields")
+
+	// ErrMixedReadAPI is returned by Read and ReadRow when the other of
+	// the two has already been called on the same Reader. Both share the
+	// same row buffer, and interleaving them would silently re-emit or
+	// skip rows instead of advancing consistently.
+	ErrMixedReadAPI = errors.New("toa5: Read and ReadRow cannot be used on the same Reader")
+)
+
+// readAPI tracks which of Read (cell-by-cell) or ReadRow (whole-row) a
+// Reader has been read through, so the other can refuse to be used on the
+// same Reader once one of them has started.
+type readAPI int
+
+const (
+	readAPIUnused readAPI = iota
+	readAPICell
+	readAPIRow
 )
 
 // Record denotes a single record in a TOA5 file with its associated metadata.
@@ -50,17 +67,26 @@ type Options struct {
 	Delimiter    rune
 }
 
+var _ RecordReader = (*Reader)(nil)
+
 // Reader is a reader for TOA5 files and wraps and csv.Reader.
 type Reader struct {
 	r            *csv.Reader  // The underling reader.
 	options      *Options     // Options for reading.
 	currentRow   []string     // Buffer for to store the current read row.
+	rowLoaded    bool         // Whether currentRow holds a row not yet returned by ReadRow.
 	columnIndex  int          // Buffer for to store the last read field.
 	rowTimestamp time.Time    // Buffer of the timestamp for the currentLine.
 	environment  *Environment // The first line in a TOA5 file.
 	fields       []string     // The second line in a TOA5 file.
 	units        []string     // The third line in a TOA5 file.
 	aggregation  []string     // The fourth line in a TOA5 file.
+
+	allColumns []int                                // Indices of all named, non-TIMESTAMP columns, precomputed after readHeader.
+	selected   []int                                // Indices kept by ReadRow; defaults to allColumns.
+	decoders   map[string]func(string) (any, error) // Per-column decoders registered via RegisterType.
+
+	api readAPI // Which of Read or ReadRow has been used, if either.
 }
 
 // NewReader will return a new TOA5 reader.
@@ -100,9 +126,18 @@ func newReader(in io.Reader, opt *Options) (*Reader, error) {
 		return nil, err
 	}
 
+	for i, name := range r.fields {
+		if i == 0 || name == "" {
+			continue
+		}
+		r.allColumns = append(r.allColumns, i)
+	}
+	r.selected = r.allColumns
+
 	if err := r.readNextRow(); err != nil {
 		return nil, err
 	}
+	r.rowLoaded = true
 
 	return r, nil
 }
@@ -195,8 +230,14 @@ func (r *Reader) readNextRow() error {
 	return err
 }
 
-// Read reads and returns a Record.
+// Read reads and returns a Record. It cannot be called on a Reader that
+// ReadRow has already been called on; see ErrMixedReadAPI.
 func (r *Reader) Read() (*Record, error) {
+	if r.api == readAPIRow {
+		return nil, ErrMixedReadAPI
+	}
+	r.api = readAPICell
+
 	r.columnIndex += 1
 
 	// We have read the last column, so we need to read a new row and continue
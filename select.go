@@ -0,0 +1,87 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Select restricts the columns returned by ReadRow to those named. Calling
+// it more than once replaces the previous selection. It returns r so calls
+// can be chained, e.g. r.Select("Batt_V_Avg").ReadRow().
+func (r *Reader) Select(names ...string) *Reader {
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+
+	selected := make([]int, 0, len(names))
+	for _, i := range r.allColumns {
+		if want[r.fields[i]] {
+			selected = append(selected, i)
+		}
+	}
+	r.selected = selected
+
+	return r
+}
+
+// RegisterType overrides the default float64 decoding of the column named
+// name. decoder is called with the raw cell value for that column whenever
+// it is read via ReadRow; it is not consulted by Read, which always parses
+// cells as float64.
+func (r *Reader) RegisterType(name string, decoder func(string) (any, error)) {
+	if r.decoders == nil {
+		r.decoders = make(map[string]func(string) (any, error))
+	}
+	r.decoders[name] = decoder
+}
+
+// ReadRow reads the next row and returns its timestamp together with the
+// decoded value of each selected column (all named columns, by default),
+// keyed by field name. Columns with a decoder registered via RegisterType
+// are decoded with it; all others are parsed as float64, with unparsable
+// cells reported as math.NaN, the same as Read. It cannot be called on a
+// Reader that Read has already been called on; see ErrMixedReadAPI.
+func (r *Reader) ReadRow() (time.Time, map[string]any, error) {
+	if r.api == readAPICell {
+		return time.Time{}, nil, ErrMixedReadAPI
+	}
+	r.api = readAPIRow
+
+	if !r.rowLoaded {
+		if err := r.readNextRow(); err != nil {
+			return time.Time{}, nil, err
+		}
+	}
+
+	row := make(map[string]any, len(r.selected))
+	for _, i := range r.selected {
+		name := r.fields[i]
+		cell := r.currentRow[i]
+
+		if decode, ok := r.decoders[name]; ok {
+			v, err := decode(cell)
+			if err != nil {
+				return time.Time{}, nil, err
+			}
+			row[name] = v
+			continue
+		}
+
+		v, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			v = math.NaN()
+		}
+		row[name] = v
+	}
+
+	ts := r.rowTimestamp
+	r.rowLoaded = false
+
+	return ts, row, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadRow(t *testing.T) {
+	r, err := NewReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, row, err := r.ReadRow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ts.Equal(parseTime(t, format, "2020-06-07 23:45")) {
+		t.Fatalf("unexpected timestamp: %v", ts)
+	}
+
+	if got := row["RECORD"]; got != 0.0 {
+		t.Fatalf("RECORD = %v, want 0", got)
+	}
+	if got := row["Batt_V_Avg"]; got != 12.52 {
+		t.Fatalf("Batt_V_Avg = %v, want 12.52", got)
+	}
+
+	_, row, err = r.ReadRow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := row["Batt_V_Avg"]; got != 12.56 {
+		t.Fatalf("Batt_V_Avg = %v, want 12.56", got)
+	}
+}
+
+func TestReadRowSelect(t *testing.T) {
+	r, err := NewReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Select("Batt_V_Avg")
+
+	_, row, err := r.ReadRow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(row) != 1 {
+		t.Fatalf("len(row) = %d, want 1", len(row))
+	}
+	if got := row["Batt_V_Avg"]; got != 12.52 {
+		t.Fatalf("Batt_V_Avg = %v, want 12.52", got)
+	}
+}
+
+func TestReadRowAfterReadIsRejected(t *testing.T) {
+	r, err := NewReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := r.ReadRow(); !errors.Is(err, ErrMixedReadAPI) {
+		t.Fatalf("got %v, want ErrMixedReadAPI", err)
+	}
+}
+
+func TestReadAfterReadRowIsRejected(t *testing.T) {
+	r, err := NewReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.ReadRow(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Read(); !errors.Is(err, ErrMixedReadAPI) {
+		t.Fatalf("got %v, want ErrMixedReadAPI", err)
+	}
+}
+
+func TestRegisterType(t *testing.T) {
+	r, err := NewReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RegisterType("RECORD", func(s string) (any, error) {
+		return "n=" + s, nil
+	})
+
+	_, row, err := r.ReadRow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := row["RECORD"]; got != "n=0" {
+		t.Fatalf("RECORD = %v, want n=0", got)
+	}
+}
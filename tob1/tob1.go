@@ -0,0 +1,206 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tob1 implements a reader for Campbell Scientific's binary TOB1
+// file format, the packed binary counterpart to TOA5.
+//
+// A TOB1 file starts with the four comma-separated ASCII header lines of
+// TOA5 (environment, field names, units and aggregation/process) plus a
+// fifth, unique to TOB1, listing each column's data type, followed
+// immediately by fixed-width binary records, one per sample.
+package tob1
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/euracresearch/toa5"
+	"github.com/euracresearch/toa5/internal/campbell"
+)
+
+// General errors.
+var (
+	ErrNoTOB1File        = errors.New("tob1: no TOB1 file")
+	ErrEnvironmentLength = errors.New("tob1: environment line has missing fields")
+
+	// ErrEmptyRecord wraps toa5.ErrEmptyRecord so callers that only know
+	// the generic toa5.RecordReader interface can detect it with
+	// errors.Is(err, toa5.ErrEmptyRecord) regardless of which format they
+	// are reading.
+	ErrEmptyRecord = fmt.Errorf("tob1: empty record name: %w", toa5.ErrEmptyRecord)
+)
+
+// Reader is a reader for TOB1 files.
+type Reader struct {
+	r           *bufio.Reader
+	environment *toa5.Environment
+	fields      []string
+	units       []string
+	aggregation []string
+	types       []string
+	sizes       []int
+	rowWidth    int
+
+	currentRow   []float64
+	rowTimestamp time.Time
+	columnIndex  int
+}
+
+var _ toa5.RecordReader = (*Reader)(nil)
+
+func init() {
+	toa5.RegisterFormat("TOB1", func(b []byte) bool {
+		return len(b) >= 4 && string(b[:4]) == "TOB1"
+	}, func(r io.Reader) (toa5.RecordReader, error) {
+		return NewReader(r)
+	})
+}
+
+// NewReader returns a new TOB1 reader.
+func NewReader(in io.Reader) (*Reader, error) {
+	r := &Reader{r: bufio.NewReader(in)}
+
+	if err := r.readHeader(); err != nil {
+		return nil, err
+	}
+
+	if err := r.readNextRow(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Environment returns the environment header line of the TOB1.
+func (r *Reader) Environment() *toa5.Environment { return r.environment }
+
+// Fields returns the field header line of the TOB1.
+func (r *Reader) Fields() []string { return r.fields }
+
+// Units returns the unit header line of the TOB1.
+func (r *Reader) Units() []string { return r.units }
+
+// Aggregation returns the process (aggregation) header line of the TOB1.
+func (r *Reader) Aggregation() []string { return r.aggregation }
+
+// readLine reads a single ASCII header line and splits it on commas. It
+// reads directly off r.r so that, unlike encoding/csv, it never buffers
+// past the newline into the binary data that follows the header.
+func (r *Reader) readLine() ([]string, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil && line == "" {
+		return nil, err
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	return strings.Split(line, ","), nil
+}
+
+func (r *Reader) readHeader() error {
+	env, err := r.readLine()
+	if err != nil {
+		return err
+	}
+	if len(env) < 8 {
+		return ErrEnvironmentLength
+	}
+
+	r.environment = &toa5.Environment{
+		Filetype:  env[0],
+		Station:   env[1],
+		Model:     env[2],
+		Serial:    env[3],
+		OSVersion: env[4],
+		Program:   env[5],
+		Signature: env[6],
+		Table:     env[7],
+	}
+
+	if r.environment.Filetype != "TOB1" {
+		return ErrNoTOB1File
+	}
+
+	if r.fields, err = r.readLine(); err != nil {
+		return err
+	}
+	if r.units, err = r.readLine(); err != nil {
+		return err
+	}
+	if r.aggregation, err = r.readLine(); err != nil {
+		return err
+	}
+	if r.types, err = r.readLine(); err != nil {
+		return err
+	}
+
+	r.sizes = make([]int, len(r.types))
+	for i, t := range r.types {
+		size, err := campbell.TypeSize(t)
+		if err != nil {
+			return err
+		}
+		r.sizes[i] = size
+		r.rowWidth += size
+	}
+
+	return nil
+}
+
+func (r *Reader) readNextRow() error {
+	row := make([]byte, r.rowWidth)
+	if _, err := io.ReadFull(r.r, row); err != nil {
+		return err
+	}
+
+	r.currentRow = make([]float64, len(r.types))
+	off := 0
+	for i, t := range r.types {
+		b := row[off : off+r.sizes[i]]
+		off += r.sizes[i]
+
+		if t == "SecNano" {
+			r.rowTimestamp = campbell.SecNano(b)
+			continue
+		}
+
+		v, err := campbell.DecodeValue(b, t)
+		if err != nil {
+			return err
+		}
+		r.currentRow[i] = v
+	}
+
+	r.columnIndex = 0
+	return nil
+}
+
+// Read reads and returns a Record.
+func (r *Reader) Read() (*toa5.Record, error) {
+	r.columnIndex += 1
+
+	if r.columnIndex >= len(r.currentRow) {
+		if err := r.readNextRow(); err != nil {
+			return nil, err
+		}
+
+		r.columnIndex += 1
+	}
+
+	name := r.fields[r.columnIndex]
+	if name == "" {
+		return nil, ErrEmptyRecord
+	}
+
+	return &toa5.Record{
+		Timestamp:   r.rowTimestamp,
+		Value:       r.currentRow[r.columnIndex],
+		Name:        name,
+		Unit:        r.units[r.columnIndex],
+		Aggregation: r.aggregation[r.columnIndex],
+	}, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tob1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func row(t *testing.T, ts time.Time, record uint32, v float32) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	since := ts.Sub(time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	binary.Write(&buf, binary.LittleEndian, uint32(since/time.Second))
+	binary.Write(&buf, binary.LittleEndian, uint32(since%time.Second))
+	binary.Write(&buf, binary.LittleEndian, record)
+	binary.Write(&buf, binary.LittleEndian, math.Float32bits(v))
+
+	return buf.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	header := "TOB1,Station,CR1000,S11,CR1000.Std.32.03,CPU:T1.CR1,4242,Table\r\n" +
+		"TIMESTAMP,RECORD,Batt_V_Avg\r\n" +
+		"TS,RN,Volts\r\n" +
+		",,Avg\r\n" +
+		"SecNano,ULONG,IEEE4\r\n"
+
+	ts := time.Date(2020, 6, 7, 23, 45, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.Write(row(t, ts, 0, 12.52))
+	buf.Write(row(t, ts.Add(15*time.Minute), 1, 12.56))
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.Environment().Table; got != "Table" {
+		t.Fatalf("unexpected table: %q", got)
+	}
+
+	want := []struct {
+		name  string
+		value float64
+	}{
+		{"RECORD", 0},
+		{"Batt_V_Avg", 12.52},
+		{"RECORD", 1},
+		{"Batt_V_Avg", 12.56},
+	}
+
+	for i, w := range want {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+
+		if rec.Name != w.name {
+			t.Fatalf("%d: name = %q, want %q", i, rec.Name, w.name)
+		}
+
+		if math.Abs(rec.Value-w.value) > 1e-4 {
+			t.Fatalf("%d: value = %v, want %v", i, rec.Value, w.value)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tob3 implements a reader for Campbell Scientific's binary TOB3
+// file format.
+//
+// TOB3 shares its five ASCII header lines with TOB1 (environment, field
+// names, units, aggregation/process and data types), followed by a sixth
+// line describing the frame geometry (records per frame, the record
+// interval in nanoseconds and the trailing validation stamp size). The
+// binary data that follows is split into frames: a 12-byte frame header
+// (seconds since 1990-01-01 UTC, nanoseconds within that second and a frame
+// record number) followed by the frame's rows and a validation stamp. Each
+// row's timestamp is the frame-start timestamp plus the row's index within
+// the frame times the record interval.
+package tob3
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/euracresearch/toa5"
+	"github.com/euracresearch/toa5/internal/campbell"
+)
+
+// General errors.
+var (
+	ErrNoTOB3File        = errors.New("tob3: no TOB3 file")
+	ErrEnvironmentLength = errors.New("tob3: environment line has missing fields")
+	ErrFrameHeaderLength = errors.New("tob3: frame header line has missing fields")
+
+	// ErrEmptyRecord wraps toa5.ErrEmptyRecord so callers that only know
+	// the generic toa5.RecordReader interface can detect it with
+	// errors.Is(err, toa5.ErrEmptyRecord) regardless of which format they
+	// are reading.
+	ErrEmptyRecord = fmt.Errorf("tob3: empty record name: %w", toa5.ErrEmptyRecord)
+)
+
+// frameHeaderSize is the size, in bytes, of a TOB3 frame header: seconds
+// since 1990 (uint32), nanoseconds within that second (uint32) and the
+// frame's starting record number (uint32).
+const frameHeaderSize = 12
+
+// Reader is a reader for TOB3 files.
+type Reader struct {
+	r           *bufio.Reader
+	environment *toa5.Environment
+	fields      []string
+	units       []string
+	aggregation []string
+	types       []string
+	sizes       []int
+	rowWidth    int
+
+	recordsPerFrame int
+	interval        time.Duration
+	valStampSize    int
+
+	frameStart time.Time
+	frameRow   int
+
+	currentRow   []float64
+	rowTimestamp time.Time
+	columnIndex  int
+}
+
+var _ toa5.RecordReader = (*Reader)(nil)
+
+func init() {
+	toa5.RegisterFormat("TOB3", func(b []byte) bool {
+		return len(b) >= 4 && string(b[:4]) == "TOB3"
+	}, func(r io.Reader) (toa5.RecordReader, error) {
+		return NewReader(r)
+	})
+}
+
+// NewReader returns a new TOB3 reader.
+func NewReader(in io.Reader) (*Reader, error) {
+	r := &Reader{r: bufio.NewReader(in)}
+
+	if err := r.readHeader(); err != nil {
+		return nil, err
+	}
+
+	if err := r.readNextRow(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Environment returns the environment header line of the TOB3.
+func (r *Reader) Environment() *toa5.Environment { return r.environment }
+
+// Fields returns the field header line of the TOB3.
+func (r *Reader) Fields() []string { return r.fields }
+
+// Units returns the unit header line of the TOB3.
+func (r *Reader) Units() []string { return r.units }
+
+// Aggregation returns the process (aggregation) header line of the TOB3.
+func (r *Reader) Aggregation() []string { return r.aggregation }
+
+// readLine reads a single ASCII header line and splits it on commas. It
+// reads directly off r.r so that, unlike encoding/csv, it never buffers
+// past the newline into the binary data that follows the header.
+func (r *Reader) readLine() ([]string, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil && line == "" {
+		return nil, err
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	return strings.Split(line, ","), nil
+}
+
+func (r *Reader) readHeader() error {
+	env, err := r.readLine()
+	if err != nil {
+		return err
+	}
+	if len(env) < 8 {
+		return ErrEnvironmentLength
+	}
+
+	r.environment = &toa5.Environment{
+		Filetype:  env[0],
+		Station:   env[1],
+		Model:     env[2],
+		Serial:    env[3],
+		OSVersion: env[4],
+		Program:   env[5],
+		Signature: env[6],
+		Table:     env[7],
+	}
+
+	if r.environment.Filetype != "TOB3" {
+		return ErrNoTOB3File
+	}
+
+	if r.fields, err = r.readLine(); err != nil {
+		return err
+	}
+	if r.units, err = r.readLine(); err != nil {
+		return err
+	}
+	if r.aggregation, err = r.readLine(); err != nil {
+		return err
+	}
+	if r.types, err = r.readLine(); err != nil {
+		return err
+	}
+
+	// The first column is a virtual TIMESTAMP, derived from the frame
+	// header and record interval rather than stored per row.
+	r.sizes = make([]int, len(r.types))
+	for i, t := range r.types {
+		if t == "TIMESTAMP" {
+			continue
+		}
+
+		size, err := campbell.TypeSize(t)
+		if err != nil {
+			return err
+		}
+		r.sizes[i] = size
+		r.rowWidth += size
+	}
+
+	frame, err := r.readLine()
+	if err != nil {
+		return err
+	}
+	if len(frame) < 3 {
+		return ErrFrameHeaderLength
+	}
+
+	r.recordsPerFrame, err = strconv.Atoi(frame[0])
+	if err != nil {
+		return err
+	}
+
+	intervalNanos, err := strconv.ParseInt(frame[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	r.interval = time.Duration(intervalNanos)
+
+	r.valStampSize, err = strconv.Atoi(frame[2])
+	if err != nil {
+		return err
+	}
+
+	// Force the first readNextRow call to read a fresh frame.
+	r.frameRow = r.recordsPerFrame
+	return nil
+}
+
+func (r *Reader) readNextFrame() error {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return err
+	}
+
+	r.frameStart = campbell.SecNano(header[0:8])
+	r.frameRow = 0
+	return nil
+}
+
+func (r *Reader) readNextRow() error {
+	if r.frameRow >= r.recordsPerFrame {
+		if err := r.readNextFrame(); err != nil {
+			return err
+		}
+	}
+
+	row := make([]byte, r.rowWidth)
+	if _, err := io.ReadFull(r.r, row); err != nil {
+		return err
+	}
+
+	r.currentRow = make([]float64, len(r.types))
+	off := 0
+	for i, t := range r.types {
+		if t == "TIMESTAMP" {
+			continue
+		}
+
+		v, err := campbell.DecodeValue(row[off:off+r.sizes[i]], t)
+		if err != nil {
+			return err
+		}
+		r.currentRow[i] = v
+		off += r.sizes[i]
+	}
+
+	r.rowTimestamp = r.frameStart.Add(time.Duration(r.frameRow) * r.interval)
+	r.frameRow++
+
+	// The last row of a frame is followed by the frame's validation stamp.
+	if r.frameRow >= r.recordsPerFrame && r.valStampSize > 0 {
+		stamp := make([]byte, r.valStampSize)
+		if _, err := io.ReadFull(r.r, stamp); err != nil {
+			return err
+		}
+	}
+
+	r.columnIndex = 0
+	return nil
+}
+
+// Read reads and returns a Record.
+func (r *Reader) Read() (*toa5.Record, error) {
+	r.columnIndex += 1
+
+	if r.columnIndex >= len(r.currentRow) {
+		if err := r.readNextRow(); err != nil {
+			return nil, err
+		}
+
+		r.columnIndex += 1
+	}
+
+	name := r.fields[r.columnIndex]
+	if name == "" {
+		return nil, ErrEmptyRecord
+	}
+
+	return &toa5.Record{
+		Timestamp:   r.rowTimestamp,
+		Value:       r.currentRow[r.columnIndex],
+		Name:        name,
+		Unit:        r.units[r.columnIndex],
+		Aggregation: r.aggregation[r.columnIndex],
+	}, nil
+}
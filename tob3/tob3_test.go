@@ -0,0 +1,88 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tob3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func frameHeader(t *testing.T, ts time.Time) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	since := ts.Sub(time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	binary.Write(&buf, binary.LittleEndian, uint32(since/time.Second))
+	binary.Write(&buf, binary.LittleEndian, uint32(since%time.Second))
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // frame record number
+
+	return buf.Bytes()
+}
+
+func row(t *testing.T, record uint32, v float32) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, record)
+	binary.Write(&buf, binary.LittleEndian, math.Float32bits(v))
+
+	return buf.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	header := "TOB3,Station,CR1000,S11,CR1000.Std.32.03,CPU:T1.CR1,4242,Table\r\n" +
+		"TIMESTAMP,RECORD,Batt_V_Avg\r\n" +
+		"TS,RN,Volts\r\n" +
+		",,Avg\r\n" +
+		"TIMESTAMP,ULONG,IEEE4\r\n" +
+		"2,15000000000,0\r\n"
+
+	frameStart := time.Date(2020, 6, 7, 23, 45, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.Write(frameHeader(t, frameStart))
+	buf.Write(row(t, 0, 12.52))
+	buf.Write(row(t, 1, 12.56))
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name  string
+		value float64
+		ts    time.Time
+	}{
+		{"RECORD", 0, frameStart},
+		{"Batt_V_Avg", 12.52, frameStart},
+		{"RECORD", 1, frameStart.Add(15 * time.Second)},
+		{"Batt_V_Avg", 12.56, frameStart.Add(15 * time.Second)},
+	}
+
+	for i, w := range want {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+
+		if rec.Name != w.name {
+			t.Fatalf("%d: name = %q, want %q", i, rec.Name, w.name)
+		}
+
+		if math.Abs(rec.Value-w.value) > 1e-4 {
+			t.Fatalf("%d: value = %v, want %v", i, rec.Value, w.value)
+		}
+
+		if !rec.Timestamp.Equal(w.ts) {
+			t.Fatalf("%d: timestamp = %v, want %v", i, rec.Timestamp, w.ts)
+		}
+	}
+}
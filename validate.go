@@ -0,0 +1,213 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrNotEnoughRows is returned by DetectInterval when a Reader reaches EOF
+// before enough rows were seen to infer an interval.
+var ErrNotEnoughRows = errors.New("not enough rows to detect interval")
+
+// detectIntervalSampleSize is the number of rows DetectInterval reads ahead
+// to infer the logging interval.
+const detectIntervalSampleSize = 20
+
+// DetectInterval reads ahead through r and returns the modal delta between
+// the timestamps of consecutive rows, which is assumed to be the logger's
+// configured scan interval. It consumes the rows it reads, so it should be
+// called on a freshly opened Reader, before any other row is read from it.
+func (r *Reader) DetectInterval() (time.Duration, error) {
+	counts := make(map[time.Duration]int)
+
+	var prev time.Time
+	have := false
+
+	for i := 0; i < detectIntervalSampleSize; i++ {
+		ts, _, err := r.ReadRow()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if have {
+			counts[ts.Sub(prev)]++
+		}
+		prev = ts
+		have = true
+	}
+
+	var mode time.Duration
+	var best int
+	for d, n := range counts {
+		if n > best {
+			mode, best = d, n
+		}
+	}
+	if best == 0 {
+		return 0, ErrNotEnoughRows
+	}
+
+	return mode, nil
+}
+
+// Gap describes a run of consecutive timestamps missing from a TOA5 file,
+// inferred from a jump between two rows larger than the expected interval.
+type Gap struct {
+	Start          time.Time
+	End            time.Time
+	MissingRecords int
+}
+
+// Gaps reads r to completion and returns every Gap where consecutive rows
+// are spaced further apart than expected, allowing for up to tolerance as
+// jitter before a gap is reported. A tolerance <= 0 defaults to half of
+// expected. It consumes every row of r.
+func (r *Reader) Gaps(expected, tolerance time.Duration) ([]Gap, error) {
+	if tolerance <= 0 {
+		tolerance = expected / 2
+	}
+
+	var gaps []Gap
+	var prev time.Time
+	have := false
+
+	for {
+		ts, _, err := r.ReadRow()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return gaps, err
+		}
+
+		if have {
+			if delta := ts.Sub(prev); delta > expected+tolerance {
+				gaps = append(gaps, Gap{
+					Start:          prev,
+					End:            ts,
+					MissingRecords: int(delta/expected) - 1,
+				})
+			}
+		}
+
+		prev = ts
+		have = true
+	}
+
+	return gaps, nil
+}
+
+// IssueType identifies the kind of problem a ValidationIssue reports.
+type IssueType string
+
+// Issue types reported by Validate.
+const (
+	NonMonotonicTimestamp IssueType = "non_monotonic_timestamp"
+	DuplicateTimestamp    IssueType = "duplicate_timestamp"
+	NaNBurst              IssueType = "nan_burst"
+)
+
+// ValidationIssue describes a single problem found by Validate.
+type ValidationIssue struct {
+	Type      IssueType
+	Timestamp time.Time
+	Message   string
+}
+
+// ValidateOptions configures Validate.
+type ValidateOptions struct {
+	// NaNBurstThreshold is the number of consecutive NaN values in a
+	// single column at or above which that run is reported as a NaNBurst.
+	// It defaults to 5.
+	NaNBurstThreshold int
+}
+
+// Validate reads r to completion and reports monotonicity violations,
+// duplicate timestamps and, per column, runs of consecutive NaN values. It
+// consumes every row of r.
+//
+// Validate does not detect mid-file changes of units or aggregation: a
+// single Reader only ever sees the one header it was opened with, so such
+// a change can only happen across separate files or Reader instances,
+// which is out of scope for this function.
+func (r *Reader) Validate(opts ValidateOptions) []ValidationIssue {
+	threshold := opts.NaNBurstThreshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+
+	var issues []ValidationIssue
+
+	var prev time.Time
+	have := false
+
+	nanRuns := make(map[string]int)
+	nanRunStarts := make(map[string]time.Time)
+
+	flushNaNBurst := func(name string) {
+		if nanRuns[name] < threshold {
+			return
+		}
+		issues = append(issues, ValidationIssue{
+			Type:      NaNBurst,
+			Timestamp: nanRunStarts[name],
+			Message:   fmt.Sprintf("column %s: %d consecutive NaN values starting at %s", name, nanRuns[name], nanRunStarts[name]),
+		})
+	}
+
+	for {
+		ts, row, err := r.ReadRow()
+		if err != nil {
+			break
+		}
+
+		if have {
+			switch {
+			case ts.Before(prev):
+				issues = append(issues, ValidationIssue{
+					Type:      NonMonotonicTimestamp,
+					Timestamp: ts,
+					Message:   fmt.Sprintf("timestamp %s is before previous timestamp %s", ts, prev),
+				})
+			case ts.Equal(prev):
+				issues = append(issues, ValidationIssue{
+					Type:      DuplicateTimestamp,
+					Timestamp: ts,
+					Message:   fmt.Sprintf("duplicate timestamp %s", ts),
+				})
+			}
+		}
+
+		for name, v := range row {
+			f, ok := v.(float64)
+			if !ok || !math.IsNaN(f) {
+				flushNaNBurst(name)
+				nanRuns[name] = 0
+				continue
+			}
+
+			if nanRuns[name] == 0 {
+				nanRunStarts[name] = ts
+			}
+			nanRuns[name]++
+		}
+
+		prev = ts
+		have = true
+	}
+	for name := range nanRuns {
+		flushNaNBurst(name)
+	}
+
+	return issues
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const gapFixture = `TOA5,Station,CR1000,S11,CR1000.Std.32.03,CPU:T1.CR1,4242,Table
+TIMESTAMP,RECORD,Batt_V_Avg,,,,,
+TS,RN,Volts,,,,,
+,,Avg,,,,,
+2020-06-07 23:45,0,12.52,,,,,
+2020-06-07 23:50,1,12.53,,,,,
+2020-06-07 23:55,2,12.54,,,,,
+2020-06-08 00:15,3,12.50,,,,,
+2020-06-08 00:20,4,12.51,,,,,
+`
+
+func TestDetectInterval(t *testing.T) {
+	r, err := NewReader(strings.NewReader(gapFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.DetectInterval()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 5 * time.Minute; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestGaps(t *testing.T) {
+	r, err := NewReader(strings.NewReader(gapFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.Gaps(5*time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Gap{
+		{
+			Start:          parseTime(t, format, "2020-06-07 23:55"),
+			End:            parseTime(t, format, "2020-06-08 00:15"),
+			MissingRecords: 3,
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGapsWithExplicitTolerance(t *testing.T) {
+	r, err := NewReader(strings.NewReader(gapFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A tolerance wide enough to absorb the 20-minute jump between
+	// 23:55 and 00:15 means it is no longer reported as a gap.
+	got, err := r.Gaps(5*time.Minute, 20*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 0 {
+		t.Fatalf("got %v, want no gaps", got)
+	}
+}
+
+const validateFixture = `TOA5,Station,CR1000,S11,CR1000.Std.32.03,CPU:T1.CR1,4242,Table
+TIMESTAMP,RECORD,Batt_V_Avg,,,,,
+TS,RN,Volts,,,,,
+,,Avg,,,,,
+2020-06-07 23:45,0,12.52,,,,,
+2020-06-07 23:45,1,12.53,,,,,
+2020-06-07 23:40,2,12.54,,,,,
+2020-06-07 23:55,3,NAN,,,,,
+2020-06-08 00:00,4,NAN,,,,,
+`
+
+func TestValidate(t *testing.T) {
+	r, err := NewReader(strings.NewReader(validateFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := r.Validate(ValidateOptions{NaNBurstThreshold: 2})
+
+	var types []IssueType
+	for _, issue := range issues {
+		types = append(types, issue.Type)
+	}
+
+	want := []IssueType{DuplicateTimestamp, NonMonotonicTimestamp, NaNBurst}
+	if diff := cmp.Diff(want, types); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,506 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchOptions configures NewWatcher.
+type WatchOptions struct {
+	// Options are passed to the underlying line parsing; if nil, the
+	// defaults used by NewReader apply.
+	Options *Options
+
+	// PollInterval is used to check path for new data in addition to
+	// fsnotify, and on its own if the watched directory can't be opened
+	// with fsnotify (e.g. on network filesystems). It defaults to 5s;
+	// a negative value disables polling entirely.
+	PollInterval time.Duration
+
+	// StateFile is where the last read offset and timestamp are
+	// persisted so that a restart resumes rather than re-emitting old
+	// rows. It defaults to path with a ".toa5state" suffix.
+	StateFile string
+}
+
+// Watcher tails a TOA5 file that a CR1000 logger appends to over time,
+// delivering newly written Records on its Records channel as they appear.
+// It resumes from the last persisted offset on restart, re-reads the
+// header if the file is truncated or rotated out from under it, and
+// de-duplicates rows it has already emitted by timestamp and RECORD.
+// Truncation and rotation are detected only by the file being smaller than
+// the last read offset; a replacement file that has already grown past that
+// offset is read on from it as if it were the same file.
+type Watcher struct {
+	path      string
+	opts      *WatchOptions
+	stateFile string
+
+	records chan *Record
+	errc    chan error
+	closeCh chan struct{}
+	closed  chan struct{}
+}
+
+// watchState is the persisted, and resumed-from, state of a Watcher.
+type watchState struct {
+	Offset      int64        `json:"offset"`
+	Environment *Environment `json:"environment"`
+	Fields      []string     `json:"fields"`
+	Units       []string     `json:"units"`
+	Aggregation []string     `json:"aggregation"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Record      float64      `json:"record"`
+}
+
+func (s *watchState) headerRead() bool { return s.Fields != nil }
+
+// NewWatcher starts watching path for appended TOA5 rows. Callers must
+// call Close when done with the Watcher to release its resources.
+func NewWatcher(path string, opts *WatchOptions) (*Watcher, error) {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+
+	stateFile := opts.StateFile
+	if stateFile == "" {
+		stateFile = path + ".toa5state"
+	}
+
+	w := &Watcher{
+		path:      path,
+		opts:      opts,
+		stateFile: stateFile,
+		records:   make(chan *Record),
+		errc:      make(chan error, 1),
+		closeCh:   make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		fsw = nil
+	} else if err := fsw.Add(filepath.Dir(path)); err != nil {
+		fsw.Close()
+		fsw = nil
+	}
+
+	go w.run(fsw)
+
+	return w, nil
+}
+
+// Records returns the channel new Records are delivered on. The channel is
+// closed when Close is called or the Watcher stops because of an
+// unrecoverable error, which is then available from Err.
+func (w *Watcher) Records() <-chan *Record { return w.records }
+
+// Err returns the error that caused the Records channel to close on its
+// own, or nil if Close was called or the Watcher is still running.
+func (w *Watcher) Err() error {
+	select {
+	case err := <-w.errc:
+		w.errc <- err
+		return err
+	default:
+		return nil
+	}
+}
+
+// Close stops the Watcher and waits for it to release its resources.
+func (w *Watcher) Close() error {
+	select {
+	case <-w.closeCh:
+	default:
+		close(w.closeCh)
+	}
+	<-w.closed
+	return nil
+}
+
+func (w *Watcher) fail(err error) {
+	w.errc <- err
+}
+
+func (w *Watcher) run(fsw *fsnotify.Watcher) {
+	defer close(w.closed)
+	defer close(w.records)
+	if fsw != nil {
+		defer fsw.Close()
+	}
+
+	interval := w.opts.PollInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var events <-chan fsnotify.Event
+	var fsErrors <-chan error
+	if fsw != nil {
+		events = fsw.Events
+		fsErrors = fsw.Errors
+	}
+
+	state := w.loadState()
+
+	for {
+		if err := w.tail(&state); err != nil {
+			w.fail(err)
+			return
+		}
+
+		if !w.waitForChange(events, fsErrors, tick) {
+			return
+		}
+	}
+}
+
+// waitForChange blocks until there is reason to call tail again: a
+// PollInterval tick, an fsnotify error, or an fsnotify event for path
+// itself. Events for other files in the watched directory (notably this
+// Watcher's own state file writes) are ignored without waking tail, so
+// that saveState's rename doesn't retrigger a read of path. It returns
+// false once Close has been called.
+func (w *Watcher) waitForChange(events <-chan fsnotify.Event, fsErrors <-chan error, tick <-chan time.Time) bool {
+	for {
+		select {
+		case <-w.closeCh:
+			return false
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if filepath.Clean(ev.Name) != filepath.Clean(w.path) {
+				continue
+			}
+			return true
+		case <-fsErrors:
+			return true
+		case <-tick:
+			return true
+		}
+	}
+}
+
+// tail reads and emits every complete row appended to path since state,
+// updating and persisting state as it goes. It returns nil once it has
+// drained whatever is currently available, leaving any trailing partial
+// line for the next call.
+func (w *Watcher) tail(state *watchState) error {
+	f, err := os.Open(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The file is smaller than where we left off: it was truncated or
+	// replaced (log rotation). Start over from the top.
+	if info.Size() < state.Offset {
+		*state = watchState{}
+	}
+
+	if !state.headerRead() {
+		if err := w.readHeader(f, state); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		w.saveState(state)
+	}
+
+	// readHeader's lineReader may have buffered past the header into data
+	// rows that it then discarded; seek back to the known-good offset so
+	// the main read loop below sees every row exactly once.
+	if _, err := f.Seek(state.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	opt := w.opts.Options
+	delim := byte(',')
+	if opt != nil && opt.Delimiter != 0 {
+		delim = byte(opt.Delimiter)
+	}
+
+	br := newLineReader(f, delim)
+	for {
+		line, n, err := br.readLine()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if line == nil {
+			return nil
+		}
+
+		if err := w.emitRow(line, state); err != nil {
+			if err == errWatcherClosed {
+				return nil
+			}
+			return err
+		}
+
+		state.Offset += n
+		w.saveState(state)
+	}
+}
+
+// readHeader reads all four TOA5 header lines before committing anything
+// to state, so a header split across several writes to the file (a logger
+// or sync process flushing lines separately, or a Watcher started the
+// instant the file is created) leaves state untouched on a partial read:
+// headerRead keeps reporting false and the next tail call retries the
+// whole header, rather than getting wedged with Fields set but
+// Units/Aggregation missing.
+func (w *Watcher) readHeader(f *os.File, state *watchState) error {
+	opt := w.opts.Options
+	delim := byte(',')
+	if opt != nil && opt.Delimiter != 0 {
+		delim = byte(opt.Delimiter)
+	}
+
+	br := newLineReader(f, delim)
+
+	env, n, err := br.readLine()
+	if err != nil {
+		return err
+	}
+	if len(env) < 8 {
+		return ErrEnvironmentLength
+	}
+	if env[0] != "TOA5" {
+		return ErrNoTOA5File
+	}
+	offset := n
+
+	var fields, units, aggregation []string
+	for _, dst := range []*[]string{&fields, &units, &aggregation} {
+		line, n, err := br.readLine()
+		if err != nil {
+			return err
+		}
+		*dst = line
+		offset += n
+	}
+
+	state.Environment = &Environment{
+		Filetype:  env[0],
+		Station:   env[1],
+		Model:     env[2],
+		Serial:    env[3],
+		OSVersion: env[4],
+		Program:   env[5],
+		Signature: env[6],
+		Table:     env[7],
+	}
+	state.Fields = fields
+	state.Units = units
+	state.Aggregation = aggregation
+	state.Offset += offset
+
+	return nil
+}
+
+// errWatcherClosed is returned by emitRow when Close interrupts sending a
+// row, so that tail stops without persisting an offset past it.
+var errWatcherClosed = errors.New("toa5: watcher closed")
+
+// emitRow parses a data line and, unless it is not newer than the last row
+// this Watcher has emitted (an earlier timestamp, or the same timestamp with
+// a RECORD no greater), sends one Record per named, non-TIMESTAMP column on
+// w.records. It returns errWatcherClosed if Close is called before the
+// whole row has been sent.
+func (w *Watcher) emitRow(line []string, state *watchState) error {
+	if len(line) == 0 {
+		return nil
+	}
+
+	opt := w.opts.Options
+	layout := "2006-01-02 15:04:05"
+	loc := time.UTC
+	if opt != nil {
+		if opt.TimeLayout != "" {
+			layout = opt.TimeLayout
+		}
+		if opt.TimeLocation != nil {
+			loc = opt.TimeLocation
+		}
+	}
+
+	ts, err := time.ParseInLocation(layout, line[0], loc)
+	if err != nil {
+		ts, err = time.ParseInLocation("2006-01-02 15:04", line[0], loc)
+		if err != nil {
+			return err
+		}
+	}
+
+	var record float64
+	for i, name := range state.Fields {
+		if name == "RECORD" && i < len(line) {
+			record, _ = strconv.ParseFloat(line[i], 64)
+			break
+		}
+	}
+
+	if ts.Before(state.Timestamp) || (ts.Equal(state.Timestamp) && record <= state.Record) {
+		return nil
+	}
+
+	for i, name := range state.Fields {
+		if i == 0 || name == "" || i >= len(line) {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(line[i], 64)
+		if err != nil {
+			v = math.NaN()
+		}
+
+		rec := &Record{
+			Timestamp:   ts,
+			Value:       v,
+			Name:        name,
+			Unit:        at(state.Units, i),
+			Aggregation: at(state.Aggregation, i),
+		}
+
+		select {
+		case w.records <- rec:
+		case <-w.closeCh:
+			return errWatcherClosed
+		}
+	}
+
+	state.Timestamp = ts
+	state.Record = record
+
+	return nil
+}
+
+func at(s []string, i int) string {
+	if i < len(s) {
+		return s[i]
+	}
+	return ""
+}
+
+func (w *Watcher) loadState() watchState {
+	var state watchState
+
+	data, err := os.ReadFile(w.stateFile)
+	if err != nil {
+		return state
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return watchState{}
+	}
+
+	return state
+}
+
+func (w *Watcher) saveState(state *watchState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+
+	tmp := w.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	os.Rename(tmp, w.stateFile)
+}
+
+// lineReader reads CSV lines off r one at a time, reporting the exact
+// number of bytes each line consumed so callers can persist a byte offset
+// that is always aligned to a row boundary.
+type lineReader struct {
+	r     io.Reader
+	delim byte
+	buf   []byte
+}
+
+func newLineReader(r io.Reader, delim byte) *lineReader {
+	return &lineReader{r: r, delim: delim}
+}
+
+// readLine returns the next complete, newline-terminated CSV line as its
+// fields, along with the number of bytes (including the newline) it
+// consumed. It returns a nil line and io.EOF if only a partial line (or
+// nothing) remains, without consuming those trailing bytes from r's
+// perspective, i.e. a later readLine call starting from the same point
+// will see them again.
+func (lr *lineReader) readLine() ([]string, int64, error) {
+	chunk := make([]byte, 4096)
+	for {
+		if i := indexByte(lr.buf, '\n'); i >= 0 {
+			line := lr.buf[:i+1]
+			lr.buf = lr.buf[i+1:]
+
+			fields, err := parseCSVLine(string(line), lr.delim)
+			if err != nil {
+				return nil, 0, err
+			}
+			return fields, int64(len(line)), nil
+		}
+
+		n, err := lr.r.Read(chunk)
+		if n > 0 {
+			lr.buf = append(lr.buf, chunk[:n]...)
+			continue
+		}
+		if err != nil {
+			return nil, 0, io.EOF
+		}
+	}
+}
+
+func indexByte(b []byte, c byte) int {
+	for i, x := range b {
+		if x == c {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseCSVLine(line string, delim byte) ([]string, error) {
+	cr := csv.NewReader(strings.NewReader(line))
+	cr.Comma = rune(delim)
+	cr.TrimLeadingSpace = true
+	return cr.Read()
+}
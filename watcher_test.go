@@ -0,0 +1,192 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const watcherHeader = "TOA5,Station,CR1000,S11,CR1000.Std.32.03,CPU:T1.CR1,4242,Table\r\n" +
+	"TIMESTAMP,RECORD,Batt_V_Avg,,,,,\r\n" +
+	"TS,RN,Volts,,,,,\r\n" +
+	",,Avg,,,,,\r\n"
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.dat")
+
+	if err := os.WriteFile(path, []byte(watcherHeader+"2020-06-07 23:45:00,0,12.52,,,,,\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatcher(path, &WatchOptions{PollInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	rec := <-w.Records()
+	if rec == nil {
+		t.Fatalf("got nil record, err: %v", w.Err())
+	}
+	if rec.Name != "RECORD" || rec.Value != 0 {
+		t.Fatalf("got %+v, want RECORD=0", rec)
+	}
+
+	rec = <-w.Records()
+	if rec.Name != "Batt_V_Avg" || rec.Value != 12.52 {
+		t.Fatalf("got %+v, want Batt_V_Avg=12.52", rec)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("2020-06-08 00:00:00,1,12.56,,,,,\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	select {
+	case rec := <-w.Records():
+		if rec.Name != "RECORD" || rec.Value != 1 {
+			t.Fatalf("got %+v, want RECORD=1", rec)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for appended record")
+	}
+}
+
+// TestWatcherEmitsPreExistingRow guards against readHeader's lineReader
+// over-reading past the header into the first data row and discarding it.
+// StateFile is pointed outside the watched directory and polling is
+// disabled so that nothing but a correct initial read can deliver the
+// record: there is no fsnotify self-trigger from saveState's rename to
+// mask the bug.
+func TestWatcherEmitsPreExistingRow(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.dat")
+
+	if err := os.WriteFile(path, []byte(watcherHeader+"2020-06-07 23:45:00,0,12.52,,,,,\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stateDir := t.TempDir()
+	w, err := NewWatcher(path, &WatchOptions{
+		PollInterval: -1,
+		StateFile:    filepath.Join(stateDir, "data.dat.toa5state"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	select {
+	case rec := <-w.Records():
+		if rec == nil {
+			t.Fatalf("got nil record, err: %v", w.Err())
+		}
+		if rec.Name != "RECORD" || rec.Value != 0 {
+			t.Fatalf("got %+v, want RECORD=0", rec)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for pre-existing record")
+	}
+}
+
+// TestWatcherHeaderSplitAcrossWrites guards against readHeader wedging the
+// Watcher when the four header lines don't all arrive in one write: the
+// Environment and Fields lines land first, and only later are Units,
+// Aggregation and the first data row appended.
+func TestWatcherHeaderSplitAcrossWrites(t *testing.T) {
+	const (
+		headerEnv    = "TOA5,Station,CR1000,S11,CR1000.Std.32.03,CPU:T1.CR1,4242,Table\r\n"
+		headerFields = "TIMESTAMP,RECORD,Batt_V_Avg,,,,,\r\n"
+		headerUnits  = "TS,RN,Volts,,,,,\r\n"
+		headerAgg    = ",,Avg,,,,,\r\n"
+	)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.dat")
+
+	if err := os.WriteFile(path, []byte(headerEnv+headerFields), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatcher(path, &WatchOptions{PollInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Give the Watcher a chance to see the partial header on its own
+	// before the rest of it, and the first data row, are appended.
+	time.Sleep(50 * time.Millisecond)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(headerUnits + headerAgg + "2020-06-07 23:45:00,0,12.52,,,,,\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	select {
+	case rec := <-w.Records():
+		if rec == nil {
+			t.Fatalf("got nil record, err: %v", w.Err())
+		}
+		if rec.Name != "RECORD" || rec.Value != 0 {
+			t.Fatalf("got %+v, want RECORD=0", rec)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for record after header arrived across two writes")
+	}
+}
+
+func TestWatcherResumesFromState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.dat")
+
+	if err := os.WriteFile(path, []byte(watcherHeader+"2020-06-07 23:45:00,0,12.52,,,,,\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatcher(path, &WatchOptions{PollInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-w.Records()
+	<-w.Records()
+	w.Close()
+
+	w2, err := NewWatcher(path, &WatchOptions{PollInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w2.Close()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("2020-06-08 00:00:00,1,12.56,,,,,\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	select {
+	case rec := <-w2.Records():
+		if rec.Name != "RECORD" || rec.Value != 1 {
+			t.Fatalf("got %+v, want RECORD=1, not a re-emitted old row", rec)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for appended record")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Writer writes TOA5 files and wraps a csv.Writer.
+type Writer struct {
+	w           *csv.Writer
+	options     *Options
+	environment *Environment
+	fields      []string
+	units       []string
+	aggregation []string
+}
+
+// NewWriter returns a new Writer that writes to out. It immediately writes
+// the four TOA5 header lines derived from env, fields, units and
+// aggregation.
+//
+// If opt is nil, the default Options used by NewReader are applied.
+func NewWriter(out io.Writer, env *Environment, fields, units, aggregation []string, opt *Options) (*Writer, error) {
+	if opt == nil {
+		opt = &Options{}
+	}
+
+	if opt.TimeLocation == nil {
+		opt.TimeLocation = time.UTC
+	}
+
+	if opt.TimeLayout == "" {
+		opt.TimeLayout = "2006-01-02 15:04:05"
+	}
+
+	if opt.Delimiter == 0 {
+		opt.Delimiter = ','
+	}
+
+	csvw := csv.NewWriter(out)
+	csvw.Comma = opt.Delimiter
+
+	w := &Writer{
+		w:           csvw,
+		options:     opt,
+		environment: env,
+		fields:      fields,
+		units:       units,
+		aggregation: aggregation,
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *Writer) writeHeader() error {
+	env := w.environment
+	if env == nil {
+		env = &Environment{}
+	}
+
+	if err := w.w.Write([]string{
+		"TOA5",
+		env.Station,
+		env.Model,
+		env.Serial,
+		env.OSVersion,
+		env.Program,
+		env.Signature,
+		env.Table,
+	}); err != nil {
+		return err
+	}
+
+	if err := w.w.Write(w.fields); err != nil {
+		return err
+	}
+
+	if err := w.w.Write(w.units); err != nil {
+		return err
+	}
+
+	if err := w.w.Write(w.aggregation); err != nil {
+		return err
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Write appends records to the underlying writer. It expects records to be
+// grouped into rows in the same order as the header written by NewWriter,
+// i.e. len(records) is a multiple of the number of data columns
+// (len(fields)-1, the TIMESTAMP column excluded) and records[i] belongs to
+// column i%n.
+func (w *Writer) Write(records []*Record) error {
+	n := len(w.fields) - 1
+	if n <= 0 {
+		return ErrEmptyRecord
+	}
+
+	for i := 0; i < len(records); i += n {
+		end := i + n
+		if end > len(records) {
+			end = len(records)
+		}
+
+		row := records[i:end]
+		values := make([]float64, len(row))
+		for j, r := range row {
+			values[j] = r.Value
+		}
+
+		if err := w.WriteRow(row[0].Timestamp, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteRow formats and writes a single data row for the given timestamp and
+// column values.
+func (w *Writer) WriteRow(timestamp time.Time, values []float64) error {
+	row := make([]string, 0, len(values)+1)
+	row = append(row, timestamp.In(w.options.TimeLocation).Format(w.options.TimeLayout))
+
+	for _, v := range values {
+		row = append(row, formatFloat(v))
+	}
+
+	if err := w.w.Write(row); err != nil {
+		return err
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func formatFloat(v float64) string {
+	if math.IsNaN(v) {
+		return "NAN"
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 Eurac Research. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package toa5
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	env := &Environment{
+		Station:   "Station",
+		Model:     "CR1000",
+		Serial:    "S11",
+		OSVersion: "CR1000.Std.32.03",
+		Program:   "CPU:T1.CR1",
+		Signature: "4242",
+		Table:     "Table",
+	}
+	fields := []string{"TIMESTAMP", "RECORD", "Batt_V_Avg"}
+	units := []string{"TS", "RN", "Volts"}
+	aggregation := []string{"", "", "Avg"}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, env, fields, units, aggregation, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := parseTime(t, format, "2020-06-07 23:45")
+	if err := w.WriteRow(ts, []float64{0, 12.52}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(ts, []float64{1, math.NaN()}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "TOA5,Station,CR1000,S11,CR1000.Std.32.03,CPU:T1.CR1,4242,Table\n" +
+		"TIMESTAMP,RECORD,Batt_V_Avg\n" +
+		"TS,RN,Volts\n" +
+		",,Avg\n" +
+		"2020-06-07 23:45:00,0,12.52\n" +
+		"2020-06-07 23:45:00,1,NAN\n"
+
+	if got := buf.String(); got != want {
+		t.Fatalf("mismatch:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestWriterRoundtrip(t *testing.T) {
+	env := &Environment{
+		Station:   "Station",
+		Model:     "CR1000",
+		Serial:    "S11",
+		OSVersion: "CR1000.Std.32.03",
+		Program:   "CPU:T1.CR1",
+		Signature: "4242",
+		Table:     "Table",
+	}
+	fields := []string{"TIMESTAMP", "RECORD", "Batt_V_Avg", "", "", "", "", ""}
+	units := []string{"TS", "RN", "Volts", "", "", "", "", ""}
+	aggregation := []string{"", "", "Avg", "", "", "", "", ""}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, env, fields, units, aggregation, &Options{TimeLayout: format})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := parseTime(t, format, "2020-06-07 23:45")
+	if err := w.WriteRow(ts, []float64{0, 12.52, math.NaN(), math.NaN(), math.NaN(), math.NaN(), math.NaN()}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.Environment(); got.Station != env.Station || got.Table != env.Table {
+		t.Fatalf("environment mismatch: %+v", got)
+	}
+
+	rec, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Name != "RECORD" || rec.Value != 0 {
+		t.Fatalf("unexpected first record: %+v", rec)
+	}
+
+	rec, err = r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Name != "Batt_V_Avg" || rec.Value != 12.52 || !rec.Timestamp.Equal(ts) {
+		t.Fatalf("unexpected second record: %+v", rec)
+	}
+}